@@ -0,0 +1,85 @@
+package flowlog
+
+import (
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// cborFlowRate mirrors FlowRate but uses small integer keys instead of
+// string field names to keep per-record CBOR overhead low.
+type cborFlowRate struct {
+	SerialNo  string   `cbor:"0,keyasint"`
+	Ts        int64    `cbor:"1,keyasint"`
+	Value     *float64 `cbor:"2,keyasint"`
+	TempGroup *uint16  `cbor:"3,keyasint,omitempty"`
+}
+
+// EncodeFlowRatesCBOR writes series to w as an indefinite-length CBOR array
+// of integer-keyed maps, one per FlowRate. It is a much smaller wire format
+// than the equivalent JSON array, which matters once a single pause code
+// has been expanded into thousands of near-identical records.
+func EncodeFlowRatesCBOR(w io.Writer, series []*FlowRate) error {
+	enc, err := NewCBORStreamEncoder(w)
+	if err != nil {
+		return err
+	}
+	for _, rate := range series {
+		if err := enc.Encode(rate); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// CBORStreamEncoder streams FlowRates out as a single indefinite-length
+// CBOR array, used by EncodeFlowRatesCBOR, ProcessFlowLogs, and any caller
+// (such as a pipeline sink) that needs to write a CBOR series incrementally
+// rather than building the whole slice up front.
+type CBORStreamEncoder struct {
+	enc *cbor.Encoder
+}
+
+// NewCBORStreamEncoder starts an indefinite-length CBOR array on w and
+// returns an encoder for writing FlowRates into it one at a time. Callers
+// must call Close once done to terminate the array.
+func NewCBORStreamEncoder(w io.Writer) (*CBORStreamEncoder, error) {
+	enc := cbor.NewEncoder(w)
+	if err := enc.StartIndefiniteArray(); err != nil {
+		return nil, err
+	}
+	return &CBORStreamEncoder{enc: enc}, nil
+}
+
+func (e *CBORStreamEncoder) Encode(rate *FlowRate) error {
+	return e.enc.Encode(cborFlowRate{
+		SerialNo:  rate.SerialNo,
+		Ts:        rate.Ts,
+		Value:     rate.Value,
+		TempGroup: rate.TempGroup,
+	})
+}
+
+func (e *CBORStreamEncoder) Close() error {
+	return e.enc.EndIndefinite()
+}
+
+// DecodeFlowRatesCBOR reads a series previously written by
+// EncodeFlowRatesCBOR.
+func DecodeFlowRatesCBOR(r io.Reader) ([]*FlowRate, error) {
+	var rows []cborFlowRate
+	if err := cbor.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	series := make([]*FlowRate, 0, len(rows))
+	for _, row := range rows {
+		series = append(series, &FlowRate{
+			SerialNo:  row.SerialNo,
+			Ts:        row.Ts,
+			Value:     row.Value,
+			TempGroup: row.TempGroup,
+		})
+	}
+	return series, nil
+}
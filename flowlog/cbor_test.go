@@ -0,0 +1,37 @@
+package flowlog
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecodeFlowRatesCBORRoundTrip(t *testing.T) {
+	v1, v2 := 1.5, 0.0
+	series := []*FlowRate{
+		{SerialNo: "dev-1", Ts: 1000, Value: &v1},
+		{SerialNo: "dev-1", Ts: 1001, Value: &v2},
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeFlowRatesCBOR(&buf, series); err != nil {
+		t.Fatalf("EncodeFlowRatesCBOR: %v", err)
+	}
+
+	got, err := DecodeFlowRatesCBOR(&buf)
+	if err != nil {
+		t.Fatalf("DecodeFlowRatesCBOR: %v", err)
+	}
+
+	if len(got) != len(series) {
+		t.Fatalf("got %d records, want %d", len(got), len(series))
+	}
+	for i := range series {
+		if got[i].SerialNo != series[i].SerialNo || got[i].Ts != series[i].Ts {
+			t.Fatalf("record %d: got %+v, want %+v", i, got[i], series[i])
+		}
+		if !reflect.DeepEqual(*got[i].Value, *series[i].Value) {
+			t.Fatalf("record %d value: got %v, want %v", i, *got[i].Value, *series[i].Value)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package flowlog
+
+import "errors"
+
+// ErrTruncatedCode is returned by DecompressFlowLog when the byte-code
+// stream ends mid-way through a 16-bit code (i.e. an odd number of bytes
+// were supplied for a code that started a long-form sequence).
+var ErrTruncatedCode = errors.New("flowlog: truncated 16-bit code at end of buffer")
@@ -0,0 +1,259 @@
+// Package flowlog decodes the compact byte-code flow logs emitted by field
+// devices into expanded, per-second flow rate series.
+package flowlog
+
+import (
+	"time"
+)
+
+// FlowLog is a single device report: a serial number, a base timestamp, radio
+// stats, and the raw byte-code series to decompress.
+type FlowLog struct {
+	SerialNo string  `json:"serial_no"`
+	Ts       int64   `json:"ts"`
+	RSSI     float32 `json:"rssi"`
+	SNR      float32 `json:"snr"`
+	Codes    []byte  `json:"codes"`
+}
+
+// FlowLogCode struct
+type FlowLogCode struct {
+	Value     uint16          `json:"value,omitempty"`
+	Type      FlowLogCodeType `json:"type,omitempty"`
+	Ts        int64           `json:"ts,omitempty"`
+	TempGroup *uint16         `json:"temp,omitempty"`
+}
+
+// TemperatureCode implements FlowerPointer interface
+func (fc *FlowLogCode) TemperatureCode() *uint16 {
+	return fc.TempGroup
+}
+
+// CodeType implements FlowerPointer interface
+func (fc *FlowLogCode) CodeType() *FlowLogCodeType {
+	return &fc.Type
+}
+
+// Float implements FlowerPointer interface
+func (fc *FlowLogCode) Float() *float64 {
+	v := fc.toFloat()
+	return &v
+}
+
+// Q2 implements FlowerPointer interface
+func (fc *FlowLogCode) Q2() *uint16 {
+	return &fc.Value
+}
+
+func (fc *FlowLogCode) toFloat() float64 {
+	switch fc.Type {
+	case 0:
+		return float64(fc.Value) / 4.0
+	default:
+		return float64(fc.Value)
+	}
+}
+
+// FlowLogCodeType opts codes
+type FlowLogCodeType int
+
+func (f FlowLogCodeType) String() string {
+	switch int(f) {
+	case 1, 2:
+		return "absolute"
+	case 3:
+		return "pause"
+	case 4:
+		return "dt"
+	case 5:
+		return "temp"
+	default:
+		return "n/a"
+	}
+}
+
+// Flow log code types
+const (
+	FlowAbsoluteCodeType FlowLogCodeType = 1
+	FlowCodeType         FlowLogCodeType = 2
+	PauseLengthCodeType  FlowLogCodeType = 3
+	DeltaFlowLogCodeType FlowLogCodeType = 4
+	TempGroupCodeType    FlowLogCodeType = 5
+)
+
+// DecompressFlowLogTimeSeries expands the raw byte-codes into one
+// FlowLogCode per second, starting at timestamp, and returns the whole
+// series as a slice. serialNo is only used to annotate debug logging and
+// may be left blank. It is a thin, memory-hungry wrapper around
+// DecompressFlowLogStream kept for callers that want the full series at
+// once; prefer DecompressFlowLogStream or ProcessFlowLogs for large codes.
+func DecompressFlowLogTimeSeries(serialNo string, codes []byte, timestamp *time.Time) ([]*FlowLogCode, error) {
+	out := make(chan *FlowLogCode)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- DecompressFlowLogStream(serialNo, codes, *timestamp, out)
+		close(out)
+	}()
+
+	var flowSeries []*FlowLogCode
+	for fc := range out {
+		flowSeries = append(flowSeries, fc)
+	}
+	if err := <-errCh; err != nil {
+		return nil, err
+	}
+	return flowSeries, nil
+}
+
+// Code for flow log decompression
+type Code struct {
+	Value uint16
+	Type  FlowLogCodeType
+}
+
+// DecompressFlowLog walks the raw byte-code stream and expands each 8-bit or
+// 16-bit code into a Code. serialNo is only used to annotate debug logging
+// and may be left blank. It returns ErrTruncatedCode if the stream ends
+// mid-way through a 16-bit code.
+func DecompressFlowLog(serialNo string, codes []byte) ([]Code, error) {
+	readingA16bit := false
+	code16 := uint16(0)
+	flow := uint16(0)
+	var FlowLogCodes []Code
+
+	for offset, code := range codes {
+
+		if readingA16bit {
+			code16 += uint16(code)
+			readingA16bit = false
+			if code16 >= 0xF000 {
+				pauseLength := code16 - 0xF000
+				FlowLogCodes = append(FlowLogCodes, Code{
+					Value: pauseLength,
+					Type:  PauseLengthCodeType,
+				})
+				logDecodeStep(serialNo, offset, code, PauseLengthCodeType)
+				continue
+			} else {
+				flow = code16 - 0xE000 // flow from absolute readout
+				FlowLogCodes = append(FlowLogCodes, Code{
+					Type:  FlowCodeType,
+					Value: code16 - 0xE000,
+				})
+				logDecodeStep(serialNo, offset, code, FlowCodeType)
+				continue
+			}
+		} else {
+
+			if code >= 0xE0 {
+				// #if 3 top bits are '111', this is a start of a long code
+				code16 = (uint16(code) << 8) // #shift and store the upper half of the new long code
+				readingA16bit = true
+				continue
+
+			} else {
+				// #short code
+				if code >= 0xDC {
+					//#temperature change
+					tempGroup := uint16(code) - 0xDC
+					FlowLogCodes = append(FlowLogCodes, Code{
+						Type:  TempGroupCodeType,
+						Value: tempGroup,
+					})
+					logDecodeStep(serialNo, offset, code, TempGroupCodeType)
+					continue
+				}
+				//#delta flow
+				deltaFlow := uint16(code) - 109
+				flow += deltaFlow
+				FlowLogCodes = append(FlowLogCodes, Code{
+					Type:  DeltaFlowLogCodeType,
+					Value: flow,
+				})
+				logDecodeStep(serialNo, offset, code, DeltaFlowLogCodeType)
+				continue
+			}
+		}
+
+	}
+
+	if readingA16bit {
+		return FlowLogCodes, ErrTruncatedCode
+	}
+	return FlowLogCodes, nil
+}
+
+func logDecodeStep(serialNo string, offset int, rawCode byte, codeType FlowLogCodeType) {
+	Logger.Debug().
+		Str("serial_no", serialNo).
+		Int("offset", offset).
+		Uint8("raw_code", rawCode).
+		Stringer("type", codeType).
+		Msg("decoded flow log code")
+}
+
+// FlowRate is a single decompressed, per-second flow reading for a device.
+type FlowRate struct {
+	SerialNo  string   `json:"serial_no"`
+	Ts        int64    `json:"ts"`
+	Value     *float64 `json:"value"`
+	TempGroup *uint16  `json:"temp_group,omitempty"`
+}
+
+// BuildOptions customizes BuildFlowRatesWithOptions.
+type BuildOptions struct {
+	// TempGroupRemap rewrites decoded temperature-group IDs before they are
+	// attached to FlowRate output, e.g. when a newer firmware revision uses
+	// different group numbering than older devices in the same fleet.
+	TempGroupRemap map[uint16]uint16
+	// DropPauseEntries skips the zero-valued entries
+	// DecompressFlowLogTimeSeries emits for each second of a
+	// PauseLengthCodeType run, instead of turning each one into a FlowRate.
+	DropPauseEntries bool
+}
+
+// BuildFlowRates decompresses a single FlowLog record into its FlowRate
+// series, the same transform main's batch path and the HTTP service apply
+// per record.
+func BuildFlowRates(record *FlowLog) ([]*FlowRate, error) {
+	return BuildFlowRatesWithOptions(record, BuildOptions{})
+}
+
+// BuildFlowRatesWithOptions is BuildFlowRates with pipeline-level
+// filtering and remapping applied to the decoded series before it is
+// converted to FlowRate.
+func BuildFlowRatesWithOptions(record *FlowLog, opts BuildOptions) ([]*FlowRate, error) {
+	ts := time.Unix(record.Ts, 0)
+	serie, err := DecompressFlowLogTimeSeries(record.SerialNo, record.Codes, &ts)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]*FlowRate, 0, len(serie))
+	for _, entry := range serie {
+		switch entry.Type {
+		case PauseLengthCodeType:
+			if opts.DropPauseEntries {
+				continue
+			}
+		case FlowCodeType, DeltaFlowLogCodeType:
+		default:
+			continue
+		}
+
+		tempGroup := entry.TempGroup
+		if tempGroup != nil {
+			if remapped, ok := opts.TempGroupRemap[*tempGroup]; ok {
+				tempGroup = &remapped
+			}
+		}
+
+		rates = append(rates, &FlowRate{
+			SerialNo:  record.SerialNo,
+			Value:     entry.Float(),
+			Ts:        entry.Ts,
+			TempGroup: tempGroup,
+		})
+	}
+	return rates, nil
+}
@@ -0,0 +1,23 @@
+package flowlog
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDecompressFlowLogTruncatedCode(t *testing.T) {
+	// 0xE0 starts a 16-bit code but the buffer ends before its second byte.
+	_, err := DecompressFlowLog("dev-1", []byte{0xE0})
+	if !errors.Is(err, ErrTruncatedCode) {
+		t.Fatalf("got err %v, want ErrTruncatedCode", err)
+	}
+}
+
+func TestDecompressFlowLogTimeSeriesTruncatedCode(t *testing.T) {
+	ts := time.Unix(1000, 0)
+	_, err := DecompressFlowLogTimeSeries("dev-1", []byte{110, 0xE0}, &ts)
+	if !errors.Is(err, ErrTruncatedCode) {
+		t.Fatalf("got err %v, want ErrTruncatedCode", err)
+	}
+}
@@ -0,0 +1,8 @@
+package flowlog
+
+import "github.com/rs/zerolog"
+
+// Logger is the package-level logger DecompressFlowLog uses to emit
+// per-code debug events. It defaults to a disabled logger so callers that
+// never configure logging pay no cost.
+var Logger zerolog.Logger = zerolog.Nop()
@@ -0,0 +1,148 @@
+package flowlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DecompressFlowLogStream expands codes into one FlowLogCode per second,
+// starting at ts, and sends each one to out as it is produced rather than
+// accumulating them in a slice. It closes no channels; the caller owns out.
+// serialNo is only used to annotate debug logging and may be left blank.
+func DecompressFlowLogStream(serialNo string, codes []byte, ts time.Time, out chan<- *FlowLogCode) error {
+	decompressedCodes, err := DecompressFlowLog(serialNo, codes)
+	if err != nil {
+		return err
+	}
+
+	var tempGroup *uint16
+	unixTs := ts.Unix()
+	for _, v := range decompressedCodes {
+		switch v.Type {
+		case PauseLengthCodeType:
+			for i := uint16(0); i < v.Value; i++ {
+				out <- &FlowLogCode{Ts: unixTs, Value: 0, TempGroup: tempGroup, Type: PauseLengthCodeType}
+				unixTs++
+			}
+		case FlowCodeType, DeltaFlowLogCodeType:
+			out <- &FlowLogCode{Ts: unixTs, Value: v.Value, TempGroup: tempGroup, Type: v.Type}
+			unixTs++
+		case TempGroupCodeType:
+			group := v.Value
+			tempGroup = &group
+		default:
+		}
+	}
+	return nil
+}
+
+// Options configures ProcessFlowLogs.
+type Options struct {
+	// Format is the output series encoding: "json" (the default, emitted as
+	// newline-delimited JSON) or "cbor".
+	Format string
+}
+
+// ProcessFlowLogs reads a JSON array of FlowLog records from r and writes
+// their decompressed FlowRate series to w, one record at a time. Unlike
+// BuildFlowRates run over a fully-unmarshaled []*FlowLog, it never holds
+// more than one FlowLog and its in-flight expansion in memory, so it scales
+// to multi-day exports that don't fit in RAM.
+func ProcessFlowLogs(r io.Reader, w io.Writer, opts Options) error {
+	enc, err := newSeriesEncoder(w, opts.Format)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("flowlog: expected a JSON array, got %v", tok)
+	}
+
+	for dec.More() {
+		var record FlowLog
+		if err := dec.Decode(&record); err != nil {
+			return err
+		}
+		if err := streamRecord(&record, enc); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// streamRecord decompresses a single FlowLog and writes its FlowRate series
+// to enc without ever materializing the full series as a slice.
+func streamRecord(record *FlowLog, enc seriesEncoder) error {
+	ts := time.Unix(record.Ts, 0)
+	out := make(chan *FlowLogCode)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- DecompressFlowLogStream(record.SerialNo, record.Codes, ts, out)
+		close(out)
+	}()
+
+	// out is unbuffered and DecompressFlowLogStream only returns once every
+	// send on it completes, so once enc.Encode fails we must keep draining
+	// out (discarding whatever arrives) rather than returning early, or the
+	// producer goroutine above blocks forever on its next send.
+	var encErr error
+	for fc := range out {
+		if encErr != nil {
+			continue
+		}
+		switch fc.Type {
+		case PauseLengthCodeType, FlowCodeType, DeltaFlowLogCodeType:
+			if err := enc.Encode(&FlowRate{
+				SerialNo:  record.SerialNo,
+				Value:     fc.Float(),
+				Ts:        fc.Ts,
+				TempGroup: fc.TempGroup,
+			}); err != nil {
+				encErr = err
+			}
+		default:
+		}
+	}
+
+	if err := <-errCh; err != nil {
+		return err
+	}
+	return encErr
+}
+
+// seriesEncoder writes a FlowRate series to an underlying writer one record
+// at a time, in either NDJSON or CBOR form.
+type seriesEncoder interface {
+	Encode(*FlowRate) error
+	Close() error
+}
+
+func newSeriesEncoder(w io.Writer, format string) (seriesEncoder, error) {
+	switch format {
+	case "", "json":
+		return &ndjsonSeriesEncoder{enc: json.NewEncoder(w)}, nil
+	case "cbor":
+		return NewCBORStreamEncoder(w)
+	default:
+		return nil, fmt.Errorf("flowlog: unsupported format %q: want json or cbor", format)
+	}
+}
+
+type ndjsonSeriesEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonSeriesEncoder) Encode(rate *FlowRate) error { return e.enc.Encode(rate) }
+func (e *ndjsonSeriesEncoder) Close() error                { return nil }
@@ -0,0 +1,102 @@
+package flowlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessFlowLogsNDJSON(t *testing.T) {
+	input := `[{"serial_no":"dev-1","ts":1000,"codes":"bg=="}]`
+
+	var out bytes.Buffer
+	if err := ProcessFlowLogs(strings.NewReader(input), &out, Options{}); err != nil {
+		t.Fatalf("ProcessFlowLogs: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1: %q", len(lines), out.String())
+	}
+
+	var rate FlowRate
+	if err := json.Unmarshal([]byte(lines[0]), &rate); err != nil {
+		t.Fatalf("unmarshal line: %v", err)
+	}
+	if rate.SerialNo != "dev-1" || rate.Ts != 1000 {
+		t.Fatalf("got %+v, want serial_no=dev-1 ts=1000", rate)
+	}
+}
+
+// TestDecompressFlowLogStreamPreservesTempGroupPerEntry pins down a past
+// bug where every emitted FlowLogCode's TempGroup pointer aliased the same
+// range-loop variable, so changing the temp group later in the stream
+// silently rewrote the TempGroup already attached to earlier entries.
+func TestDecompressFlowLogStreamPreservesTempGroupPerEntry(t *testing.T) {
+	// group 0, two delta-flow codes, group 3, one more delta-flow code.
+	codes := []byte{0xDC, 110, 110, 0xDC + 3, 110}
+
+	out := make(chan *FlowLogCode)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- DecompressFlowLogStream("dev-1", codes, time.Unix(1000, 0), out)
+		close(out)
+	}()
+
+	var got []*FlowLogCode
+	for fc := range out {
+		got = append(got, fc)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("DecompressFlowLogStream: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("got %d codes, want 3", len(got))
+	}
+
+	want := []uint16{0, 0, 3}
+	for i, w := range want {
+		if got[i].TempGroup == nil || *got[i].TempGroup != w {
+			t.Fatalf("entry %d temp group = %v, want %d", i, got[i].TempGroup, w)
+		}
+	}
+}
+
+// failingEncoder always fails Encode, to exercise streamRecord's error path.
+type failingEncoder struct{ err error }
+
+func (e *failingEncoder) Encode(*FlowRate) error { return e.err }
+func (e *failingEncoder) Close() error           { return nil }
+
+// TestStreamRecordDrainsOutOnEncodeError pins down a past bug where
+// streamRecord returned as soon as enc.Encode failed without draining the
+// remaining sends on out, an unbuffered channel. DecompressFlowLogStream's
+// producer goroutine would then block forever on its next send, leaking
+// the goroutine.
+func TestStreamRecordDrainsOutOnEncodeError(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// 0xF0, 0x05 decodes to a single pause code of length 5, so the
+	// producer sends 5 FlowLogCodes; the first Encode call fails, and the
+	// remaining 4 must still be drained for the producer goroutine to exit.
+	record := &FlowLog{SerialNo: "dev-1", Ts: 1000, Codes: []byte{0xF0, 0x05}}
+	wantErr := errors.New("sink unavailable")
+
+	if err := streamRecord(record, &failingEncoder{err: wantErr}); !errors.Is(err, wantErr) {
+		t.Fatalf("streamRecord: got %v, want %v", err, wantErr)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutine count settled at %d, want <= %d (producer goroutine leaked)", runtime.NumGoroutine(), before)
+		}
+		runtime.Gosched()
+		time.Sleep(time.Millisecond)
+	}
+}
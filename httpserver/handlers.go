@@ -0,0 +1,175 @@
+package httpserver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tousif47/K-NN-playground/flowlog"
+)
+
+// apiError pairs a client-facing message with the HTTP status it should map
+// to, so handlers can return one error value instead of panicking.
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+func badRequest(msg string) *apiError { return &apiError{status: http.StatusBadRequest, msg: msg} }
+
+// handleDecompress accepts a single FlowLog or a JSON array of them and
+// streams back the decompressed FlowRate series as newline-delimited JSON.
+func (s *Server) handleDecompress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, &apiError{status: http.StatusMethodNotAllowed, msg: "only POST is supported"})
+		return
+	}
+
+	records, err := decodeFlowLogs(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		rates, err := flowlog.BuildFlowRates(record)
+		if err != nil {
+			if errors.Is(err, flowlog.ErrTruncatedCode) {
+				s.logger.Warn().Str("serial_no", record.SerialNo).Err(err).Msg("skipping record with malformed codes")
+			} else {
+				s.logger.Error().Str("serial_no", record.SerialNo).Err(err).Msg("decompress failed")
+			}
+			continue
+		}
+		for _, rate := range rates {
+			if err := enc.Encode(rate); err != nil {
+				s.logger.Error().Err(err).Msg("write response failed")
+				return
+			}
+		}
+	}
+}
+
+// decompressRawRequest is the body for /decompress/raw.
+type decompressRawRequest struct {
+	SerialNo string `json:"serial_no,omitempty"` // optional, only used to annotate debug logging
+	Codes    string `json:"codes"`               // base64-encoded byte-code blob
+	Ts       int64  `json:"ts"`
+}
+
+// handleDecompressRaw accepts a base64 codes blob plus a timestamp and
+// returns the []*FlowLogCode output of DecompressFlowLogTimeSeries.
+func (s *Server) handleDecompressRaw(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, &apiError{status: http.StatusMethodNotAllowed, msg: "only POST is supported"})
+		return
+	}
+
+	var req decompressRawRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, badRequest("invalid JSON body: "+err.Error()))
+		return
+	}
+	if req.Codes == "" {
+		writeError(w, badRequest("codes must not be empty"))
+		return
+	}
+	if req.Ts == 0 {
+		writeError(w, badRequest("ts must be set"))
+		return
+	}
+
+	codes, err := base64.StdEncoding.DecodeString(req.Codes)
+	if err != nil {
+		writeError(w, badRequest("codes is not valid base64: "+err.Error()))
+		return
+	}
+
+	ts := time.Unix(req.Ts, 0)
+	series, err := flowlog.DecompressFlowLogTimeSeries(req.SerialNo, codes, &ts)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, series)
+}
+
+func decodeFlowLogs(r *http.Request) ([]*flowlog.FlowLog, error) {
+	dec := json.NewDecoder(r.Body)
+
+	var single flowlog.FlowLog
+	var batch []*flowlog.FlowLog
+
+	raw, err := decodeEither(dec, &single, &batch)
+	if err != nil {
+		return nil, badRequest("invalid JSON body: " + err.Error())
+	}
+	if raw {
+		return batch, nil
+	}
+
+	if single.SerialNo == "" {
+		return nil, badRequest("serial_no must not be empty")
+	}
+	return []*flowlog.FlowLog{&single}, nil
+}
+
+// decodeEither tries to decode the body as a single value, falling back to a
+// slice when the payload is a JSON array. It reports whether the slice form
+// was used.
+func decodeEither(dec *json.Decoder, single *flowlog.FlowLog, batch *[]*flowlog.FlowLog) (bool, error) {
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return false, err
+	}
+
+	trimmed := trimLeadingSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(raw, batch); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if err := json.Unmarshal(raw, single); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+func trimLeadingSpace(b []byte) []byte {
+	i := 0
+	for i < len(b) && (b[i] == ' ' || b[i] == '\t' || b[i] == '\n' || b[i] == '\r') {
+		i++
+	}
+	return b[i:]
+}
+
+// writeError maps an error to an HTTP status code. apiErrors carry their own
+// status; ErrTruncatedCode means the client sent a malformed codes blob, so
+// it maps to 400 too; anything else is treated as an internal error.
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		http.Error(w, apiErr.msg, apiErr.status)
+		return
+	}
+	if errors.Is(err, flowlog.ErrTruncatedCode) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
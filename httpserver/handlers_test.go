@@ -0,0 +1,146 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func newTestServer() *Server {
+	return New("", zerolog.Nop())
+}
+
+func TestHandleDecompress(t *testing.T) {
+	cases := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantLines  int
+	}{
+		{
+			name:       "valid single object",
+			body:       `{"serial_no":"dev-1","ts":1000,"codes":"bg=="}`,
+			wantStatus: http.StatusOK,
+			wantLines:  1,
+		},
+		{
+			name:       "valid array",
+			body:       `[{"serial_no":"dev-1","ts":1000,"codes":"bg=="},{"serial_no":"dev-2","ts":2000,"codes":"bg=="}]`,
+			wantStatus: http.StatusOK,
+			wantLines:  2,
+		},
+		{
+			name:       "malformed JSON",
+			body:       `{"serial_no":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing serial_no",
+			body:       `{"ts":1000,"codes":"bg=="}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			srv := newTestServer()
+
+			req := httptest.NewRequest(http.MethodPost, "/decompress", strings.NewReader(c.body))
+			rec := httptest.NewRecorder()
+
+			srv.http.Handler.ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", rec.Code, c.wantStatus, rec.Body.String())
+			}
+			if c.wantLines > 0 {
+				lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+				if len(lines) != c.wantLines {
+					t.Fatalf("got %d lines, want %d: %q", len(lines), c.wantLines, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestHandleDecompressMethodNotAllowed(t *testing.T) {
+	srv := newTestServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/decompress", nil)
+	rec := httptest.NewRecorder()
+
+	srv.http.Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHandleDecompressRaw(t *testing.T) {
+	cases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "valid request",
+			body:       `{"serial_no":"dev-1","codes":"bg==","ts":1000}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "malformed JSON",
+			body:       `{"codes":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing codes",
+			body:       `{"serial_no":"dev-1","ts":1000}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid base64 codes",
+			body:       `{"serial_no":"dev-1","codes":"not-valid-base64!","ts":1000}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			// "4A==" is the base64 encoding of a lone 0xE0 byte: the start of
+			// a 16-bit code with no second byte, so decompression fails with
+			// flowlog.ErrTruncatedCode. That's bad client input, not a server
+			// failure, so it must map to 400, not 500.
+			name:       "truncated 16-bit code maps to 400",
+			body:       `{"serial_no":"dev-1","codes":"4A==","ts":1000}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			srv := newTestServer()
+
+			req := httptest.NewRequest(http.MethodPost, "/decompress/raw", strings.NewReader(c.body))
+			rec := httptest.NewRecorder()
+
+			srv.http.Handler.ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", rec.Code, c.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleDecompressRawMethodNotAllowed(t *testing.T) {
+	srv := newTestServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/decompress/raw", nil)
+	rec := httptest.NewRecorder()
+
+	srv.http.Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
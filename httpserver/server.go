@@ -0,0 +1,56 @@
+// Package httpserver exposes the flowlog decompression routines over HTTP so
+// callers can decode flow logs without shelling out to the batch binary.
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Server serves the flow-log decompression HTTP API.
+type Server struct {
+	addr   string
+	logger zerolog.Logger
+	http   *http.Server
+}
+
+// New builds a Server listening on addr using logger for request and
+// lifecycle logging.
+func New(addr string, logger zerolog.Logger) *Server {
+	s := &Server{addr: addr, logger: logger}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/decompress", s.handleDecompress)
+	mux.HandleFunc("/decompress/raw", s.handleDecompressRaw)
+
+	s.http = &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+	return s
+}
+
+// Run starts the server and blocks until ctx is cancelled, at which point it
+// shuts down gracefully.
+func (s *Server) Run(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Info().Str("addr", s.addr).Msg("listening")
+		if err := s.http.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return s.http.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		return err
+	}
+}
@@ -1,236 +1,176 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
-)
 
-type FlowLog struct {
-	SerialNo string  `json:"serial_no"`
-	Ts       int64   `json:"ts"`
-	RSSI     float32 `json:"rssi"`
-	SNR      float32 `json:"snr"`
-	Codes    []byte  `json:"codes"`
-}
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/diode"
 
-// FlowLogCode struct
-type FlowLogCode struct {
-	Value     uint16          `json:"value,omitempty"`
-	Type      FlowLogCodeType `json:"type,omitempty"`
-	Ts        int64           `json:"ts,omitempty"`
-	TempGroup *uint16         `json:"temp,omitempty"`
-}
+	"github.com/tousif47/K-NN-playground/flowlog"
+	"github.com/tousif47/K-NN-playground/httpserver"
+	"github.com/tousif47/K-NN-playground/pipeline"
+)
 
-// TemperatureCode implements FlowerPointer interface
-func (fc *FlowLogCode) TemperatureCode() *uint16 {
-	return fc.TempGroup
+func checkError(err error) {
+	if err != nil {
+		panic(err)
+	}
 }
 
-// CodeType implements FlowerPointer interface
-func (fc *FlowLogCode) CodeType() *FlowLogCodeType {
-	return &fc.Type
+// logFlags holds the shared --log-level/--log-format flags accepted by
+// every subcommand.
+type logFlags struct {
+	level  *string
+	format *string
 }
 
-// Float implements FlowerPointer interface
-func (fc *FlowLogCode) Float() *float64 {
-	v := fc.toFloat()
-	return &v
+func addLogFlags(fs *flag.FlagSet) *logFlags {
+	return &logFlags{
+		level:  fs.String("log-level", "info", "log level: debug, info, warn, error"),
+		format: fs.String("log-format", "console", "log output format: console, json"),
+	}
 }
 
-// Q2 implements FlowerPointer interface
-func (fc *FlowLogCode) Q2() *uint16 {
-	return &fc.Value
-}
+// buildLogger configures a zerolog.Logger writing to w according to the
+// parsed --log-level/--log-format flags.
+func buildLogger(f *logFlags, w io.Writer) zerolog.Logger {
+	level, err := zerolog.ParseLevel(*f.level)
+	checkError(err)
 
-func (fc *FlowLogCode) toFloat() float64 {
-	switch fc.Type {
-	case 0:
-		return float64(fc.Value) / 4.0
-	default:
-		return float64(fc.Value)
+	var output io.Writer = w
+	if *f.format == "console" {
+		output = zerolog.ConsoleWriter{Out: w}
 	}
+
+	return zerolog.New(output).Level(level).With().Timestamp().Logger()
 }
 
-// FlowLogCodeType opts codes
-type FlowLogCodeType int
-
-func (f FlowLogCodeType) String() string {
-	switch int(f) {
-	case 1, 2:
-		return "absolute"
-	case 3:
-		return "pause"
-	case 4:
-		return "dt"
-	case 5:
-		return "temp"
-	default:
-		return "n/a"
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServe(os.Args[2:])
+		return
 	}
+	runBatch(os.Args[1:])
 }
 
-// Flow log code types
-const (
-	FlowAbsoluteCodeType FlowLogCodeType = 1
-	FlowCodeType         FlowLogCodeType = 2
-	PauseLengthCodeType  FlowLogCodeType = 3
-	DeltaFlowLogCodeType FlowLogCodeType = 4
-	TempGroupCodeType    FlowLogCodeType = 5
-)
+// runServe runs `knn-playground serve --addr :8080`, exposing the
+// decompression routines over HTTP until interrupted.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	lf := addLogFlags(fs)
+	fs.Parse(args)
 
-func DecompressFlowLogTimeSeries(codes []byte, timestamp *time.Time) ([]*FlowLogCode, error) {
-	decompressedCodes, err := DecompressFlowLog(codes)
-	if err != nil {
-		return nil, err
-	}
+	logger := buildLogger(lf, os.Stderr)
+	flowlog.Logger = logger
 
-	var flowSeries []*FlowLogCode
-	var tempGroup *uint16
-
-	j := 0 // series counter
-	ts := timestamp.Unix()
-	for _, v := range decompressedCodes {
-		switch v.Type {
-		case PauseLengthCodeType:
-			pause := v.Value
-			for i := uint16(0); i < pause; i++ {
-				flowSeries = append(flowSeries,
-					&FlowLogCode{Ts: ts, Value: 0, TempGroup: tempGroup, Type: PauseLengthCodeType})
-				ts++
-				j++
-			}
-
-		case FlowCodeType, DeltaFlowLogCodeType:
-			flowSeries = append(flowSeries,
-				&FlowLogCode{Ts: ts, Value: v.Value, TempGroup: tempGroup, Type: v.Type})
-			ts++
-			j++
-		case TempGroupCodeType:
-			tempGroup = &v.Value
-		default:
-		}
-	}
-	return flowSeries, nil
-}
+	srv := httpserver.New(*addr, logger)
 
-// Code for flow log decompression
-type Code struct {
-	Value uint16
-	Type  FlowLogCodeType
-}
-
-func DecompressFlowLog(codes []byte) ([]Code, error) {
-	readingA16bit := false
-	code16 := uint16(0)
-	flow := uint16(0)
-	var FlowLogCodes []Code
-
-	for _, code := range codes {
-
-		if readingA16bit {
-			code16 += uint16(code)
-			readingA16bit = false
-			if code16 >= 0xF000 {
-				pauseLength := code16 - 0xF000
-				FlowLogCodes = append(FlowLogCodes, Code{
-					Value: pauseLength,
-					Type:  PauseLengthCodeType,
-				})
-				continue
-			} else {
-				flow = code16 - 0xE000 // flow from absolute readout
-				FlowLogCodes = append(FlowLogCodes, Code{
-					Type:  FlowCodeType,
-					Value: code16 - 0xE000,
-				})
-				continue
-			}
-		} else {
-
-			if code >= 0xE0 {
-				// #if 3 top bits are '111', this is a start of a long code
-				code16 = (uint16(code) << 8) // #shift and store the upper half of the new long code
-				readingA16bit = true
-				continue
-
-			} else {
-				// #short code
-				if code >= 0xDC {
-					//#temperature change
-					tempGroup := uint16(code) - 0xDC
-					FlowLogCodes = append(FlowLogCodes, Code{
-						Type:  TempGroupCodeType,
-						Value: tempGroup,
-					})
-					continue
-				}
-				//#delta flow
-				deltaFlow := uint16(code) - 109
-				flow += deltaFlow
-				FlowLogCodes = append(FlowLogCodes, Code{
-					Type:  DeltaFlowLogCodeType,
-					Value: flow,
-				})
-				continue
-			}
-		}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
+	if err := srv.Run(ctx); err != nil {
+		checkError(err)
 	}
-	return FlowLogCodes, nil
 }
 
-func checkError(err error) {
-	if err != nil {
-		panic(err)
+// runBatch reproduces the original one-shot behavior: read flows.json,
+// decompress every record, write flow_rates.json. stdout is wrapped in a
+// diode so slow downstream log sinks never block decompression. Passing
+// --config switches to a YAML-described pipeline.Config instead, with its
+// own sources, sinks, per-serial filter, and temperature-group remap.
+func runBatch(args []string) {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	format := fs.String("format", "json", "output series format: json, cbor")
+	configPath := fs.String("config", "", "path to a YAML pipeline config; defaults to the built-in flows.json/flow_rates.json behavior")
+	lf := addLogFlags(fs)
+	fs.Parse(args)
+
+	if *format != "json" && *format != "cbor" {
+		checkError(fmt.Errorf("unsupported --format %q: want json or cbor", *format))
 	}
-}
 
-type FlowRate struct {
-	SerialNo string   `json:"serial_no"`
-	Ts       int64    `json:"ts"`
-	Value    *float64 `json:"value"`
-}
+	// --format only governs the legacy flows.json/flow_rates.json path; a
+	// pipeline config picks its series format per sink instead. Reject the
+	// combination explicitly rather than silently ignoring --format once a
+	// config is in play.
+	formatSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "format" {
+			formatSet = true
+		}
+	})
+	if *configPath != "" && formatSet {
+		checkError(fmt.Errorf("--format has no effect with --config; set format per sink in the pipeline config instead"))
+	}
 
-func main() {
-	jsonFile, err := os.Open("../Data/flows.json")
-	checkError(err)
-	defer jsonFile.Close()
+	// A pipeline config can point a sink at stdout, so route logs there to
+	// stderr instead of the legacy behavior's stdout to keep log lines out
+	// of the data stream.
+	logDest := os.Stdout
+	if *configPath != "" {
+		logDest = os.Stderr
+	}
 
-	byteValue, err := ioutil.ReadAll(jsonFile)
-	checkError(err)
+	// dropped is written from the diode's background poller goroutine (the
+	// alert callback) and read from main after the batch finishes, so it
+	// needs atomic access rather than a plain int.
+	var dropped int32
+	d := diode.NewWriter(logDest, 1000, 10*time.Millisecond, func(missed int) {
+		atomic.AddInt32(&dropped, int32(missed))
+	})
+	defer d.Close()
 
-	data := make([]*FlowLog, 0)
-	err = json.Unmarshal(byteValue, &data)
-	checkError(err)
+	logger := buildLogger(lf, d)
+	flowlog.Logger = logger
 
-	series := make([]*FlowRate, 0)
+	if *configPath != "" {
+		cfg, err := pipeline.Load(*configPath)
+		checkError(err)
 
-	for _, record := range data {
-		ts := time.Unix(record.Ts, 0)
-		serie, err := DecompressFlowLogTimeSeries(record.Codes, &ts)
+		err = pipeline.Run(cfg)
 		checkError(err)
-		for _, entry := range serie {
-			switch entry.Type {
-			case PauseLengthCodeType, FlowCodeType, DeltaFlowLogCodeType:
-				series = append(series, &FlowRate{
-					SerialNo: record.SerialNo,
-					Value:    entry.Float(),
-					Ts:       entry.Ts,
-				})
-			default:
-				continue
-			}
+
+		if n := atomic.LoadInt32(&dropped); n > 0 {
+			logger.Warn().Int32("dropped", n).Msg("log entries dropped by diode ring buffer")
 		}
+		fmt.Fprintln(os.Stderr, "DONE")
+		return
 	}
 
-	file, err := json.MarshalIndent(series, "", "")
+	jsonFile, err := os.Open("../Data/flows.json")
 	checkError(err)
+	defer jsonFile.Close()
 
-	err = ioutil.WriteFile("../Data/flow_rates.json", file, 0644)
+	outPath := "../Data/flow_rates.json"
+	if *format == "cbor" {
+		outPath = "../Data/flow_rates.cbor"
+	}
+
+	outFile, err := os.Create(outPath)
+	checkError(err)
+	defer outFile.Close()
+
+	// Stream flows.json straight into flow_rates.{json,cbor} via
+	// ProcessFlowLogs instead of unmarshaling the whole input array and
+	// accumulating the whole output series in memory; this is the same
+	// streaming path ProcessFlowLogs exists for (see flowlog/stream.go), and
+	// this is the O(N)-memory batch run it was written to fix. Per-record
+	// output is NDJSON rather than the old single JSON array.
+	err = flowlog.ProcessFlowLogs(jsonFile, outFile, flowlog.Options{Format: *format})
 	checkError(err)
 
+	if n := atomic.LoadInt32(&dropped); n > 0 {
+		logger.Warn().Int32("dropped", n).Msg("log entries dropped by diode ring buffer")
+	}
+
 	fmt.Println("DONE")
 }
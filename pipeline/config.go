@@ -0,0 +1,134 @@
+// Package pipeline turns a YAML config into a runnable flow-log ingestion
+// pipeline: where FlowLog batches are read from, how they are filtered and
+// remapped, and where the resulting FlowRate series are written.
+package pipeline
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a full ingestion run.
+type Config struct {
+	Sources          []Source          `yaml:"sources"`
+	Sinks            []Sink            `yaml:"sinks"`
+	Filter           Filter            `yaml:"filter"`
+	TempGroupRemap   map[uint16]uint16 `yaml:"temp_group_remap"`
+	DropPauseEntries bool              `yaml:"drop_pause_entries"`
+}
+
+// Source is a place to read FlowLog batches from: either a local file glob
+// or an HTTP URL returning a JSON array of FlowLog.
+type Source struct {
+	Glob string `yaml:"glob,omitempty"`
+	URL  string `yaml:"url,omitempty"`
+}
+
+// Sink is a place to write a decompressed FlowRate series to: a file path,
+// stdout, or an HTTP POST target.
+type Sink struct {
+	Path   string `yaml:"path,omitempty"`
+	Stdout bool   `yaml:"stdout,omitempty"`
+	URL    string `yaml:"url,omitempty"`
+
+	// Format is the series encoding written to this sink: "json" (the
+	// default, newline-delimited) or "cbor". HTTP sinks always send a
+	// single JSON body per FlowRate and ignore Format.
+	Format string `yaml:"format,omitempty"`
+}
+
+// Filter is a per-serial allow/deny list applied before decompression.
+type Filter struct {
+	Allow []string `yaml:"allow,omitempty"`
+	Deny  []string `yaml:"deny,omitempty"`
+}
+
+// Allowed reports whether serialNo passes the filter: it must not be in
+// Deny, and, if Allow is non-empty, it must be in Allow.
+func (f Filter) Allowed(serialNo string) bool {
+	for _, d := range f.Deny {
+		if d == serialNo {
+			return false
+		}
+	}
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, a := range f.Allow {
+		if a == serialNo {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads and validates a YAML pipeline config from path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("pipeline: parse %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("pipeline: %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Validate checks that cfg is runnable, returning the first problem found.
+func (c *Config) Validate() error {
+	if len(c.Sources) == 0 {
+		return fmt.Errorf("at least one source is required")
+	}
+	for i, s := range c.Sources {
+		switch {
+		case s.Glob == "" && s.URL == "":
+			return fmt.Errorf("source %d: must set glob or url", i)
+		case s.Glob != "" && s.URL != "":
+			return fmt.Errorf("source %d: set glob or url, not both", i)
+		}
+	}
+
+	if len(c.Sinks) == 0 {
+		return fmt.Errorf("at least one sink is required")
+	}
+	for i, s := range c.Sinks {
+		set := 0
+		if s.Path != "" {
+			set++
+		}
+		if s.Stdout {
+			set++
+		}
+		if s.URL != "" {
+			set++
+		}
+		if set != 1 {
+			return fmt.Errorf("sink %d: exactly one of path, stdout, or url must be set", i)
+		}
+		switch s.Format {
+		case "", "json", "cbor":
+		default:
+			return fmt.Errorf("sink %d: unsupported format %q: want json or cbor", i, s.Format)
+		}
+	}
+
+	for i, sn := range c.Filter.Allow {
+		if sn == "" {
+			return fmt.Errorf("filter.allow[%d]: must not be empty", i)
+		}
+	}
+	for i, sn := range c.Filter.Deny {
+		if sn == "" {
+			return fmt.Errorf("filter.deny[%d]: must not be empty", i)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package pipeline
+
+import "testing"
+
+func TestFilterAllowed(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter Filter
+		serial string
+		want   bool
+	}{
+		{"no filter allows everything", Filter{}, "dev-1", true},
+		{"deny wins", Filter{Deny: []string{"dev-1"}}, "dev-1", false},
+		{"allow list excludes others", Filter{Allow: []string{"dev-1"}}, "dev-2", false},
+		{"allow list includes member", Filter{Allow: []string{"dev-1"}}, "dev-1", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.filter.Allowed(c.serial); got != c.want {
+				t.Fatalf("Allowed(%q) = %v, want %v", c.serial, got, c.want)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{
+		Sources: []Source{{Glob: "*.json"}},
+		Sinks:   []Sink{{Stdout: true}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+
+	noSources := Config{Sinks: []Sink{{Stdout: true}}}
+	if err := noSources.Validate(); err == nil {
+		t.Fatal("expected error for missing sources")
+	}
+
+	ambiguousSink := Config{
+		Sources: []Source{{Glob: "*.json"}},
+		Sinks:   []Sink{{Stdout: true, Path: "out.json"}},
+	}
+	if err := ambiguousSink.Validate(); err == nil {
+		t.Fatal("expected error for sink with both stdout and path set")
+	}
+
+	cborSink := Config{
+		Sources: []Source{{Glob: "*.json"}},
+		Sinks:   []Sink{{Stdout: true, Format: "cbor"}},
+	}
+	if err := cborSink.Validate(); err != nil {
+		t.Fatalf("sink with format cbor: %v", err)
+	}
+
+	badFormat := Config{
+		Sources: []Source{{Glob: "*.json"}},
+		Sinks:   []Sink{{Stdout: true, Format: "xml"}},
+	}
+	if err := badFormat.Validate(); err == nil {
+		t.Fatal("expected error for unsupported sink format")
+	}
+}
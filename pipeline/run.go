@@ -0,0 +1,46 @@
+package pipeline
+
+import "github.com/tousif47/K-NN-playground/flowlog"
+
+// Run executes cfg: reads every source, decompresses and filters each
+// FlowLog record, and writes the resulting FlowRate series to every sink.
+func Run(cfg *Config) error {
+	sinks, closeSinks, err := openSinks(cfg.Sinks)
+	if err != nil {
+		return err
+	}
+	defer closeSinks()
+
+	opts := flowlog.BuildOptions{
+		TempGroupRemap:   cfg.TempGroupRemap,
+		DropPauseEntries: cfg.DropPauseEntries,
+	}
+
+	for _, src := range cfg.Sources {
+		records, err := readSource(src)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if !cfg.Filter.Allowed(record.SerialNo) {
+				continue
+			}
+
+			rates, err := flowlog.BuildFlowRatesWithOptions(record, opts)
+			if err != nil {
+				return err
+			}
+
+			for _, s := range sinks {
+				for _, rate := range rates {
+					if err := s.Write(rate); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
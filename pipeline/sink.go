@@ -0,0 +1,124 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tousif47/K-NN-playground/flowlog"
+)
+
+// sink is a destination a decompressed FlowRate series can be written to.
+type sink interface {
+	Write(*flowlog.FlowRate) error
+}
+
+// writerSink streams FlowRates as newline-delimited JSON to an underlying
+// io.Writer; used for both file and stdout sinks.
+type writerSink struct {
+	enc *json.Encoder
+}
+
+func (s *writerSink) Write(rate *flowlog.FlowRate) error { return s.enc.Encode(rate) }
+
+// cborSink streams FlowRates as a single indefinite-length CBOR array to an
+// underlying io.Writer; used for file and stdout sinks configured with
+// format: cbor.
+type cborSink struct {
+	enc *flowlog.CBORStreamEncoder
+}
+
+func (s *cborSink) Write(rate *flowlog.FlowRate) error { return s.enc.Encode(rate) }
+
+// Close terminates the CBOR array opened by NewCBORStreamEncoder. It does
+// not close the underlying writer; openSinks tracks that separately.
+func (s *cborSink) Close() error { return s.enc.Close() }
+
+// httpSink POSTs each FlowRate as its own JSON body to an HTTP endpoint.
+type httpSink struct {
+	url string
+}
+
+func (s *httpSink) Write(rate *flowlog.FlowRate) error {
+	body, err := json.Marshal(rate)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("pipeline: post to %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pipeline: post to %s: status %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// openSinks builds a sink for every configured Sink, along with a close
+// func that releases any files it opened.
+func openSinks(cfgs []Sink) ([]sink, func(), error) {
+	var sinks []sink
+	var closers []io.Closer
+
+	closeAll := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+
+	for _, c := range cfgs {
+		switch {
+		case c.Stdout:
+			s, err := newFileSink(os.Stdout, c.Format)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			if closer, ok := s.(io.Closer); ok {
+				closers = append(closers, closer)
+			}
+			sinks = append(sinks, s)
+		case c.Path != "":
+			f, err := os.Create(c.Path)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			s, err := newFileSink(f, c.Format)
+			if err != nil {
+				f.Close()
+				closeAll()
+				return nil, nil, err
+			}
+			if closer, ok := s.(io.Closer); ok {
+				closers = append(closers, closer)
+			}
+			closers = append(closers, f)
+			sinks = append(sinks, s)
+		case c.URL != "":
+			sinks = append(sinks, &httpSink{url: c.URL})
+		}
+	}
+
+	return sinks, closeAll, nil
+}
+
+// newFileSink builds the sink that writes to w in the given series format
+// ("" and "json" both mean newline-delimited JSON; "cbor" means a single
+// indefinite-length CBOR array). Validate has already rejected any other
+// value by the time this runs.
+func newFileSink(w io.Writer, format string) (sink, error) {
+	if format == "cbor" {
+		enc, err := flowlog.NewCBORStreamEncoder(w)
+		if err != nil {
+			return nil, err
+		}
+		return &cborSink{enc: enc}, nil
+	}
+	return &writerSink{enc: json.NewEncoder(w)}, nil
+}
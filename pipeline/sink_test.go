@@ -0,0 +1,38 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tousif47/K-NN-playground/flowlog"
+)
+
+func TestOpenSinksCBORFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.cbor")
+
+	sinks, closeAll, err := openSinks([]Sink{{Path: path, Format: "cbor"}})
+	if err != nil {
+		t.Fatalf("openSinks: %v", err)
+	}
+
+	value := 1.5
+	if err := sinks[0].Write(&flowlog.FlowRate{SerialNo: "dev-1", Ts: 1000, Value: &value}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	closeAll()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	series, err := flowlog.DecodeFlowRatesCBOR(f)
+	if err != nil {
+		t.Fatalf("DecodeFlowRatesCBOR: %v", err)
+	}
+	if len(series) != 1 || series[0].SerialNo != "dev-1" || *series[0].Value != 1.5 {
+		t.Fatalf("got %+v, want one dev-1 rate of 1.5", series)
+	}
+}
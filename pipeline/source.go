@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tousif47/K-NN-playground/flowlog"
+)
+
+// httpClient is shared by HTTP sources and sinks so requests to a stalled
+// endpoint don't block a batch run forever.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// readSource loads every FlowLog batch a Source refers to: all files
+// matching a glob, or the JSON array returned by a URL.
+func readSource(src Source) ([]*flowlog.FlowLog, error) {
+	if src.URL != "" {
+		return readURL(src.URL)
+	}
+
+	matches, err := filepath.Glob(src.Glob)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline: glob %s: %w", src.Glob, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("pipeline: glob %s matched no files", src.Glob)
+	}
+
+	var all []*flowlog.FlowLog
+	for _, path := range matches {
+		records, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, records...)
+	}
+	return all, nil
+}
+
+func readFile(path string) ([]*flowlog.FlowLog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []*flowlog.FlowLog
+	if err := json.NewDecoder(f).Decode(&records); err != nil {
+		return nil, fmt.Errorf("pipeline: decode %s: %w", path, err)
+	}
+	return records, nil
+}
+
+func readURL(url string) ([]*flowlog.FlowLog, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline: fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pipeline: fetch %s: status %s", url, resp.Status)
+	}
+
+	var records []*flowlog.FlowLog
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return nil, fmt.Errorf("pipeline: decode %s: %w", url, err)
+	}
+	return records, nil
+}